@@ -0,0 +1,243 @@
+package digparser
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Stats holds the trailing metadata dig prints after a message: timing,
+// server, transport and response size. See ParseDigOutputWithStats.
+type Stats struct {
+	QueryTime  time.Duration
+	Server     netip.AddrPort
+	ServerName string
+	Transport  string
+	When       time.Time
+	MsgSize    int
+}
+
+// FormatOption configures FormatDigOutput.
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	comments     bool
+	sectionOrder []string
+	stats        *Stats
+}
+
+var defaultSectionOrder = []string{"QUESTION", "ANSWER", "AUTHORITY", "ADDITIONAL"}
+
+func newFormatOptions() *formatOptions {
+	return &formatOptions{
+		comments:     true,
+		sectionOrder: defaultSectionOrder,
+	}
+}
+
+// WithComments toggles the ";; ..." comment lines (header, flags and section
+// headers). It defaults to true; pass false to emit bare zone-file data.
+func WithComments(enabled bool) FormatOption {
+	return func(o *formatOptions) {
+		o.comments = enabled
+	}
+}
+
+// WithSectionOrder overrides the order sections are rendered in. Valid
+// section names are "QUESTION", "ANSWER", "AUTHORITY" and "ADDITIONAL".
+// It defaults to the order dig itself uses.
+func WithSectionOrder(sections ...string) FormatOption {
+	return func(o *formatOptions) {
+		o.sectionOrder = sections
+	}
+}
+
+// WithStats appends the dig stats footer (query time, server, WHEN, MSG
+// SIZE) after the formatted message.
+func WithStats(stats *Stats) FormatOption {
+	return func(o *formatOptions) {
+		o.stats = stats
+	}
+}
+
+// FormatDigOutput renders msgs back into dig-style text, including the
+// ";; ->>HEADER<<-" line that *dns.Msg.String omits. It reproduces dig's
+// section structure and comments, but not dig's column alignment (which
+// dns.RR.String()/dns.Question.String() don't track either) — callers
+// comparing against a captured transcript should normalize whitespace
+// first, e.g. by collapsing runs of spaces/tabs as TestFormatDigOutput
+// does. The OPT pseudosection is written with dns.OPT.String, so its
+// wording is miekg/dns's ("EDNS: version 0;", "SUBNET:", "LOCAL OPT:"), not
+// dig's, and does not match a captured transcript even after that
+// normalization.
+func FormatDigOutput(msgs []*dns.Msg, opts ...FormatOption) (string, error) {
+	options := newFormatOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var sb strings.Builder
+
+	for i, m := range msgs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		if err := formatDigMessage(&sb, m, options); err != nil {
+			return "", fmt.Errorf("failed to format message %d: %w", i, err)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func formatDigMessage(sb *strings.Builder, m *dns.Msg, options *formatOptions) error {
+	var body strings.Builder
+
+	if options.comments {
+		fmt.Fprintf(&body, ";; ->>HEADER<<- opcode: %s, status: %s, id: %d\n",
+			dns.OpcodeToString[m.Opcode], dns.RcodeToString[m.Rcode], m.Id)
+		fmt.Fprintf(&body, ";; flags: %s; QUERY: %d, ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d\n\n",
+			formatDigFlags(m), len(m.Question), len(m.Answer), len(m.Ns), len(m.Extra))
+	}
+
+	if opt := m.IsEdns0(); opt != nil && options.comments {
+		body.WriteString(opt.String())
+		body.WriteString("\n")
+	}
+
+	for _, section := range options.sectionOrder {
+		switch section {
+		case "QUESTION":
+			formatDigQuestionSection(&body, m, options)
+		case "ANSWER":
+			formatDigRRSection(&body, ";; ANSWER SECTION:", m.Answer, options)
+		case "AUTHORITY":
+			formatDigRRSection(&body, ";; AUTHORITY SECTION:", m.Ns, options)
+		case "ADDITIONAL":
+			formatDigRRSection(&body, ";; ADDITIONAL SECTION:", nonOPTExtra(m), options)
+		default:
+			return fmt.Errorf("unknown section %q", section)
+		}
+	}
+
+	// Sections separate themselves from whatever follows with a blank
+	// line; when nothing follows (no stats footer) that leaves a spurious
+	// trailing blank line, so trim it down to a single newline.
+	content := body.String()
+	if options.stats == nil {
+		content = strings.TrimRight(content, "\n") + "\n"
+	}
+
+	sb.WriteString(content)
+
+	if options.stats != nil {
+		formatDigStats(sb, options.stats)
+	}
+
+	return nil
+}
+
+func formatDigQuestionSection(sb *strings.Builder, m *dns.Msg, options *formatOptions) {
+	if len(m.Question) == 0 {
+		return
+	}
+
+	if options.comments {
+		sb.WriteString(";; QUESTION SECTION:\n")
+	}
+
+	for _, q := range m.Question {
+		sb.WriteString(q.String())
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+}
+
+func formatDigRRSection(sb *strings.Builder, header string, rrs []dns.RR, options *formatOptions) {
+	if len(rrs) == 0 {
+		return
+	}
+
+	if options.comments {
+		sb.WriteString(header)
+		sb.WriteString("\n")
+	}
+
+	for _, rr := range rrs {
+		sb.WriteString(rr.String())
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+}
+
+func formatDigStats(sb *strings.Builder, stats *Stats) {
+	fmt.Fprintf(sb, ";; Query time: %d msec\n", stats.QueryTime.Milliseconds())
+
+	if stats.Server.IsValid() {
+		name := stats.ServerName
+		if name == "" {
+			name = stats.Server.Addr().String()
+		}
+		fmt.Fprintf(sb, ";; SERVER: %s#%d(%s)", stats.Server.Addr(), stats.Server.Port(), name)
+		if stats.Transport != "" {
+			fmt.Fprintf(sb, " (%s)", stats.Transport)
+		}
+		sb.WriteString("\n")
+	}
+
+	if !stats.When.IsZero() {
+		fmt.Fprintf(sb, ";; WHEN: %s\n", stats.When.Format("Mon Jan 02 15:04:05 MST 2006"))
+	}
+
+	fmt.Fprintf(sb, ";; MSG SIZE  rcvd: %d\n", stats.MsgSize)
+}
+
+// formatDigFlags renders the message header flags in dig's canonical order:
+// qr aa tc rd ra ad cd.
+func formatDigFlags(m *dns.Msg) string {
+	var flags []string
+
+	if m.Response {
+		flags = append(flags, "qr")
+	}
+	if m.Authoritative {
+		flags = append(flags, "aa")
+	}
+	if m.Truncated {
+		flags = append(flags, "tc")
+	}
+	if m.RecursionDesired {
+		flags = append(flags, "rd")
+	}
+	if m.RecursionAvailable {
+		flags = append(flags, "ra")
+	}
+	if m.AuthenticatedData {
+		flags = append(flags, "ad")
+	}
+	if m.CheckingDisabled {
+		flags = append(flags, "cd")
+	}
+
+	return strings.Join(flags, " ")
+}
+
+// nonOPTExtra returns m.Extra with the OPT pseudo-record, if any, filtered
+// out; it is rendered separately as the OPT PSEUDOSECTION.
+func nonOPTExtra(m *dns.Msg) []dns.RR {
+	var extra []dns.RR
+
+	for _, rr := range m.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+
+	return extra
+}
@@ -0,0 +1,49 @@
+package digparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestFormatDigOutput checks that parsing a dig transcript and formatting
+// it back reproduces the original structure and content, including the
+// ->>HEADER<<- line that *dns.Msg.String omits. The comparison is
+// whitespace-normalized, not byte-identical: dig pads columns (domain
+// name, TTL, ...) to align on fixed tab stops depending on field width,
+// while dns.RR.String()/dns.Question.String() always emit a single tab,
+// so exact column alignment can't be reproduced from a parsed message.
+func TestFormatDigOutput(t *testing.T) {
+	files := testDataCollector(t, "testdata")
+
+	if len(files) == 0 {
+		t.Fatal("no testdata found")
+	}
+
+	for _, file := range files {
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed reading test file %q: %v", file, err)
+		}
+
+		data := string(bytes)
+		messages, err := ParseDigOutput(data)
+		if err != nil {
+			t.Errorf("%s: failed parsing dig output: %v", file, err)
+			continue
+		}
+
+		actual, err := FormatDigOutput(messages)
+		if err != nil {
+			t.Errorf("%s: failed formatting dig output: %v", file, err)
+			continue
+		}
+
+		expected := strings.Trim(whitespaceNormalizer(data), "\n")
+		actual = strings.Trim(whitespaceNormalizer(actual), "\n")
+
+		if expected != actual {
+			t.Errorf("%s\nExpected:\n%v\n\nActual:\n%v\n", file, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package digparser
+
+import (
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// TestParseDigOutputMultiline checks that RRSIG/DNSKEY/SOA records wrapped
+// across multiple lines in dig's +multiline form are joined back into a
+// single logical line before being handed to dns.NewRR.
+func TestParseDigOutputMultiline(t *testing.T) {
+	data, err := os.ReadFile("testdata/multiline/signed.txt")
+	if err != nil {
+		t.Fatalf("failed reading test file: %v", err)
+	}
+
+	messages, err := ParseDigOutput(string(data))
+	if err != nil {
+		t.Fatalf("failed parsing +multiline dig output: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+
+	m := messages[0]
+	if len(m.Answer) != 3 {
+		t.Fatalf("expected 3 answer records, got %d", len(m.Answer))
+	}
+
+	// Re-serialize via FormatDigOutput (single-line RR form, with the
+	// ->>HEADER<<- line ParseDigOutput dispatches on) and verify parsing
+	// that back produces the same message.
+	formatted, err := FormatDigOutput([]*dns.Msg{m})
+	if err != nil {
+		t.Fatalf("failed formatting parsed message: %v", err)
+	}
+
+	reparsed, err := ParseDigOutput(formatted)
+	if err != nil {
+		t.Fatalf("failed re-parsing formatted message: %v", err)
+	}
+
+	if len(reparsed) != 1 {
+		t.Fatalf("expected 1 re-parsed message, got %d", len(reparsed))
+	}
+
+	if reparsed[0].String() != m.String() {
+		t.Errorf("round-trip mismatch:\nExpected:\n%s\n\nActual:\n%s\n", m.String(), reparsed[0].String())
+	}
+}
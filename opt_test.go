@@ -0,0 +1,101 @@
+package digparser
+
+import (
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// TestParseDigOutputOpt checks that the OPT pseudosection is decoded into
+// the corresponding EDNS0 sub-options.
+//
+// The fixture uses dig's own wording (e.g. "CLIENT-SUBNET", "(good)",
+// quoted NSID ASCII, "KEY-TAG"), which is what real dig transcripts look
+// like. miekg/dns has no dedicated type for some of these (notably
+// edns-key-tag), and its own OPT.String() renders a different wording
+// ("SUBNET", no COOKIE status, "LOCAL OPT" for key tags, PADDING as a hex
+// dump rather than a byte count) — re-parsing that rendering drops
+// CLIENT-SUBNET and KEY-TAG outright, since parseDigOpt only recognizes
+// dig's own labels, and reads the all-zero PADDING hex dump as a count of 0
+// bytes. So unlike the rest of
+// testdata, this fixture deliberately does not get round-trip coverage from
+// TestParseDigOutput/TestFormatDigOutput's generic corpus walk (it lives
+// under testdata/opt, not *.dig); it is checked against the decoded values
+// here instead. See TestFormatDigOutput for the textual round-trip over the
+// corpus that the library can actually reproduce.
+func TestParseDigOutputOpt(t *testing.T) {
+	data, err := os.ReadFile("testdata/opt/edns.txt")
+	if err != nil {
+		t.Fatalf("failed reading test file: %v", err)
+	}
+
+	messages, err := ParseDigOutput(string(data))
+	if err != nil {
+		t.Fatalf("failed parsing dig output: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+
+	opt := messages[0].IsEdns0()
+	if opt == nil {
+		t.Fatal("expected an OPT record, got none")
+	}
+
+	if opt.Version() != 0 {
+		t.Errorf("expected EDNS version 0, got %d", opt.Version())
+	}
+	if !opt.Do() {
+		t.Error("expected DO bit set")
+	}
+	if opt.UDPSize() != 4096 {
+		t.Errorf("expected UDP size 4096, got %d", opt.UDPSize())
+	}
+
+	var (
+		gotCookie  *dns.EDNS0_COOKIE
+		gotNSID    *dns.EDNS0_NSID
+		gotSubnet  *dns.EDNS0_SUBNET
+		gotPadding *dns.EDNS0_PADDING
+		gotKeyTag  *dns.EDNS0_LOCAL
+		gotExpire  *dns.EDNS0_EXPIRE
+	)
+
+	for _, o := range opt.Option {
+		switch v := o.(type) {
+		case *dns.EDNS0_COOKIE:
+			gotCookie = v
+		case *dns.EDNS0_NSID:
+			gotNSID = v
+		case *dns.EDNS0_SUBNET:
+			gotSubnet = v
+		case *dns.EDNS0_PADDING:
+			gotPadding = v
+		case *dns.EDNS0_LOCAL:
+			gotKeyTag = v
+		case *dns.EDNS0_EXPIRE:
+			gotExpire = v
+		}
+	}
+
+	if gotCookie == nil || gotCookie.Cookie != "61626364616263646566666768696a6b" {
+		t.Errorf("unexpected COOKIE option: %+v", gotCookie)
+	}
+	if gotNSID == nil || gotNSID.Nsid != "6162636465666768" {
+		t.Errorf("unexpected NSID option: %+v", gotNSID)
+	}
+	if gotSubnet == nil || gotSubnet.Address.String() != "1.2.3.0" || gotSubnet.SourceNetmask != 24 || gotSubnet.SourceScope != 0 {
+		t.Errorf("unexpected SUBNET option: %+v", gotSubnet)
+	}
+	if gotPadding == nil || len(gotPadding.Padding) != 64 {
+		t.Errorf("unexpected PADDING option: %+v", gotPadding)
+	}
+	if gotKeyTag == nil || gotKeyTag.Code != 0xe || len(gotKeyTag.Data) != 4 {
+		t.Errorf("unexpected KEY-TAG option: %+v", gotKeyTag)
+	}
+	if gotExpire == nil || gotExpire.Expire != 86400 {
+		t.Errorf("unexpected EXPIRE option: %+v", gotExpire)
+	}
+}
@@ -1,8 +1,9 @@
-package main
+package digparser
 
 import (
-	"bufio"
+	"encoding/binary"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -16,70 +17,39 @@ var sectionParsers = map[string]ParserFunc{
 	"FLAGS":      parseDigFlags,
 	"QUESTION":   parseDigQuestion,
 	"ANSWER":     parseDigAnswer,
+	"AUTHORITY":  parseDigAuthority,
 	"ADDITIONAL": parseDigAdditional,
+	"OPT":        parseDigOpt,
 }
 
+// ParseDigOutput parses dig output held entirely in memory. It is
+// implemented on top of ParseDigStream; callers with very large or
+// streaming input should use ParseDigStream directly.
 func ParseDigOutput(data string) ([]*dns.Msg, error) {
-	const (
-		headerSection     = ";; ->>HEADER<<- "
-		flagsSection      = ";; flags: "
-		questionSection   = ";; QUESTION SECTION:"
-		answerSection     = ";; ANSWER SECTION:"
-		additionalSection = ";; ADDITIONAL SECTION:"
-	)
-
 	var messages []*dns.Msg
 
-	m := &dns.Msg{
-		Question: []dns.Question{},
-		Answer:   []dns.RR{},
-		Extra:    []dns.RR{},
-	}
-	s := bufio.NewScanner(strings.NewReader(data))
-
-	currentParser := (ParserFunc)(nil)
-
-	for s.Scan() {
-		line := s.Text()
-
-		switch {
-		case strings.HasPrefix(line, headerSection):
-			currentParser = sectionParsers["HEADER"]
-			if m.Id > 0 {
-				messages = append(messages, m)
-				m = &dns.Msg{}
-			}
-		case strings.HasPrefix(line, flagsSection):
-			currentParser = sectionParsers["FLAGS"]
-		case strings.HasPrefix(line, questionSection):
-			currentParser = sectionParsers["QUESTION"]
-			continue
-		case strings.HasPrefix(line, answerSection):
-			currentParser = sectionParsers["ANSWER"]
-			continue
-		case strings.HasPrefix(line, additionalSection):
-			currentParser = sectionParsers["ADDITIONAL"]
-			continue
-		}
-
-		if currentParser != nil {
-			if err := currentParser(line, m); err != nil {
-				return nil, fmt.Errorf("failed to parse section: %v", err)
-			}
-		}
-	}
-
-	if m.Id > 0 {
+	err := ParseDigStream(strings.NewReader(data), func(m *dns.Msg) error {
 		messages = append(messages, m)
-	}
-
-	if err := s.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %v", err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return messages, nil
 }
 
+// isRecordSection reports whether section holds RRs that may be wrapped
+// across multiple lines using dig's +multiline parenthesized form.
+func isRecordSection(section string) bool {
+	switch section {
+	case "ANSWER", "AUTHORITY", "ADDITIONAL":
+		return true
+	default:
+		return false
+	}
+}
+
 // parseHeader sets header values on the provided *dns.Msg by parsing data:
 // ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1
 func parseDigHeader(data string, m *dns.Msg) error {
@@ -180,6 +150,24 @@ func parseDigAnswer(data string, m *dns.Msg) error {
 	return nil
 }
 
+// parseDigAuthority sets values on the provided *dns.Msg by parsing data:
+// ;; AUTHORITY SECTION:
+// example.com.	3600	IN	SOA	ns1.example.com. hostmaster.example.com. 1 7200 3600 1209600 3600
+func parseDigAuthority(data string, m *dns.Msg) error {
+	if strings.TrimSpace(data) == "" {
+		return nil
+	}
+
+	rr, err := dns.NewRR(data)
+	if err != nil {
+		return err
+	}
+
+	m.Ns = append(m.Ns, rr)
+
+	return nil
+}
+
 // parseDigAdditional sets values on the provided *dns.Msg by parsing data:
 // ;; ADDITIONAL SECTION:
 // maas.  30  IN  A  127.0.0.1
@@ -198,6 +186,174 @@ func parseDigAdditional(data string, m *dns.Msg) error {
 	return nil
 }
 
+// parseDigOpt sets EDNS0 values on the provided *dns.Msg by parsing data from
+// the OPT pseudosection, e.g.:
+// ;; OPT PSEUDOSECTION:
+// ; EDNS: version: 0, flags: do; udp: 4096
+// ; COOKIE: 61626364616263646566666768696a6b (good)
+// ; NSID: 6162636465666768 ("abcdefgh")
+// ; CLIENT-SUBNET: 1.2.3.0/24/0
+// ; PADDING: 64 bytes
+// ; KEY-TAG: 12345,54321
+// ; EXPIRE: 86400
+func parseDigOpt(data string, m *dns.Msg) error {
+	line := strings.TrimPrefix(strings.TrimSpace(data), "; ")
+	if line == "" {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(line, "EDNS:"):
+		return parseDigOptEdns(line, m)
+	case strings.HasPrefix(line, "COOKIE:"):
+		value, _, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "COOKIE:")), " (")
+		if len(value) < 16 {
+			return fmt.Errorf("invalid COOKIE value '%s': client cookie must be 16 hex chars", value)
+		}
+		// value is the client cookie (first 16 hex chars) followed by an
+		// optional server cookie; dns.EDNS0_COOKIE round-trips the pair as
+		// a single hex string, so no further split is needed here.
+		opt := optRR(m)
+		opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: value})
+	case strings.HasPrefix(line, "NSID:"):
+		opt := optRR(m)
+		value, _, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(line, "NSID:")), " (")
+		opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: value})
+	case strings.HasPrefix(line, "CLIENT-SUBNET:"):
+		return parseDigOptSubnet(strings.TrimSpace(strings.TrimPrefix(line, "CLIENT-SUBNET:")), m)
+	case strings.HasPrefix(line, "PADDING:"):
+		value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(line, "PADDING:")), "bytes"))
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid PADDING value '%s': %w", value, err)
+		}
+		opt := optRR(m)
+		opt.Option = append(opt.Option, &dns.EDNS0_PADDING{Padding: make([]byte, n)})
+	case strings.HasPrefix(line, "KEY-TAG:"):
+		value := strings.TrimSpace(strings.TrimPrefix(line, "KEY-TAG:"))
+		// miekg/dns has no dedicated EDNS Key Tag (RFC 8145) option type,
+		// so the tags are carried as raw wire data via EDNS0_LOCAL; code
+		// 0xe is the registered option code for edns-key-tag.
+		var data []byte
+		for _, tag := range strings.Split(value, ",") {
+			n, err := strconv.ParseUint(strings.TrimSpace(tag), 10, 16)
+			if err != nil {
+				return fmt.Errorf("invalid KEY-TAG value '%s': %w", tag, err)
+			}
+			data = binary.BigEndian.AppendUint16(data, uint16(n))
+		}
+		opt := optRR(m)
+		opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: 0xe, Data: data})
+	case strings.HasPrefix(line, "EXPIRE:"):
+		value := strings.TrimSpace(strings.TrimPrefix(line, "EXPIRE:"))
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid EXPIRE value '%s': %w", value, err)
+		}
+		opt := optRR(m)
+		opt.Option = append(opt.Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Expire: uint32(n)})
+	}
+
+	return nil
+}
+
+// parseDigOptEdns parses the EDNS summary line that dig prints as the first
+// line of the OPT pseudosection, e.g.:
+// EDNS: version: 0, flags: do; udp: 4096
+func parseDigOptEdns(line string, m *dns.Msg) error {
+	fields := strings.Split(strings.NewReplacer(";", ",").Replace(strings.TrimPrefix(line, "EDNS:")), ",")
+
+	var udpSize uint64 = dns.DefaultMsgSize
+	var version uint64
+	var do bool
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "version":
+			n, err := strconv.ParseUint(value, 10, 8)
+			if err != nil {
+				return fmt.Errorf("invalid EDNS version '%s': %w", value, err)
+			}
+			version = n
+		case "flags":
+			do = strings.Contains(value, "do")
+		case "udp":
+			n, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return fmt.Errorf("invalid EDNS udp size '%s': %w", value, err)
+			}
+			udpSize = n
+		}
+	}
+
+	m.SetEdns0(uint16(udpSize), do)
+	optRR(m).SetVersion(uint8(version))
+
+	return nil
+}
+
+// parseDigOptSubnet parses the EDNS Client Subnet option value, e.g.:
+// 1.2.3.0/24/0
+func parseDigOptSubnet(value string, m *dns.Msg) error {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid CLIENT-SUBNET value '%s'", value)
+	}
+
+	ip := net.ParseIP(parts[0])
+	if ip == nil {
+		return fmt.Errorf("invalid CLIENT-SUBNET address '%s'", parts[0])
+	}
+
+	sourceNetmask, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid CLIENT-SUBNET source netmask '%s': %w", parts[1], err)
+	}
+
+	sourceScope, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid CLIENT-SUBNET source scope '%s': %w", parts[2], err)
+	}
+
+	subnet := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(sourceNetmask),
+		SourceScope:   uint8(sourceScope),
+		Address:       ip,
+	}
+
+	if ip.To4() != nil {
+		subnet.Family = 1
+	} else {
+		subnet.Family = 2
+	}
+
+	opt := optRR(m)
+	opt.Option = append(opt.Option, subnet)
+
+	return nil
+}
+
+// optRR returns the message's OPT record, creating and appending one to
+// m.Extra if it is not already present.
+func optRR(m *dns.Msg) *dns.OPT {
+	if opt := m.IsEdns0(); opt != nil {
+		return opt
+	}
+
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	m.Extra = append(m.Extra, opt)
+
+	return opt
+}
+
 func invertMap[K, V comparable](m map[K]V) map[V]K {
 	inv := make(map[V]K, len(m))
 
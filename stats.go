@@ -0,0 +1,124 @@
+package digparser
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ParsedMessage pairs a parsed *dns.Msg with the dig stats footer that
+// followed it, if any.
+type ParsedMessage struct {
+	Msg   *dns.Msg
+	Stats *Stats
+}
+
+// ParseDigOutputWithStats behaves like ParseDigOutput but additionally
+// parses the trailing stats/metadata block dig prints after each message,
+// e.g.:
+// ;; Query time: 12 msec
+// ;; SERVER: 1.1.1.1#53(1.1.1.1) (UDP)
+// ;; WHEN: Mon Jan 02 15:04:05 UTC 2006
+// ;; MSG SIZE  rcvd: 96
+// The stats lines may appear in any order and terminate a message before the
+// next ->>HEADER<<-.
+func ParseDigOutputWithStats(data string) ([]*ParsedMessage, error) {
+	var messages []*ParsedMessage
+
+	stats := &Stats{}
+
+	err := scanDigStream(strings.NewReader(data), defaultScannerBufferSize,
+		func(m *dns.Msg) error {
+			messages = append(messages, &ParsedMessage{Msg: m, Stats: stats})
+			stats = &Stats{}
+			return nil
+		},
+		func(line string) error {
+			if err := parseDigStatsLine(line, stats); err != nil {
+				return fmt.Errorf("failed to parse stats: %v", err)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// parseDigStatsLine sets a single field on stats by parsing one line of the
+// dig stats footer.
+func parseDigStatsLine(line string, stats *Stats) error {
+	switch {
+	case strings.HasPrefix(line, ";; Query time:"):
+		value := strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(line, ";; Query time:")), "msec")
+		ms, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid query time '%s': %w", value, err)
+		}
+		stats.QueryTime = time.Duration(ms) * time.Millisecond
+	case strings.HasPrefix(line, ";; SERVER:"):
+		return parseDigStatsServer(strings.TrimSpace(strings.TrimPrefix(line, ";; SERVER:")), stats)
+	case strings.HasPrefix(line, ";; WHEN:"):
+		value := strings.TrimSpace(strings.TrimPrefix(line, ";; WHEN:"))
+		when, err := time.Parse("Mon Jan 02 15:04:05 MST 2006", value)
+		if err != nil {
+			return fmt.Errorf("invalid WHEN value '%s': %w", value, err)
+		}
+		stats.When = when
+	case strings.HasPrefix(line, ";; MSG SIZE"):
+		_, value, ok := strings.Cut(line, "rcvd:")
+		if !ok {
+			return fmt.Errorf("invalid MSG SIZE line '%s'", line)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid MSG SIZE value '%s': %w", value, err)
+		}
+		stats.MsgSize = size
+	}
+
+	return nil
+}
+
+// parseDigStatsServer parses the SERVER line value, e.g.:
+// 1.1.1.1#53(1.1.1.1) (UDP)
+func parseDigStatsServer(value string, stats *Stats) error {
+	transport := ""
+	if i := strings.LastIndex(value, " ("); i != -1 && strings.HasSuffix(value, ")") {
+		transport = strings.TrimSuffix(value[i+2:], ")")
+		value = value[:i]
+	}
+
+	addrPart, name, ok := strings.Cut(value, "(")
+	if !ok {
+		return fmt.Errorf("invalid SERVER value '%s'", value)
+	}
+	name = strings.TrimSuffix(name, ")")
+
+	host, port, ok := strings.Cut(addrPart, "#")
+	if !ok {
+		return fmt.Errorf("invalid SERVER address '%s'", addrPart)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("invalid SERVER address '%s': %w", host, err)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid SERVER port '%s': %w", port, err)
+	}
+
+	stats.Server = netip.AddrPortFrom(addr, uint16(p))
+	stats.ServerName = name
+	stats.Transport = transport
+
+	return nil
+}
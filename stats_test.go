@@ -0,0 +1,87 @@
+package digparser
+
+import (
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDigOutputWithStats(t *testing.T) {
+	data, err := os.ReadFile("testdata/stats/basic.txt")
+	if err != nil {
+		t.Fatalf("failed reading test file: %v", err)
+	}
+
+	messages, err := ParseDigOutputWithStats(string(data))
+	if err != nil {
+		t.Fatalf("failed parsing dig output: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+
+	stats := messages[0].Stats
+
+	if stats.QueryTime != 23*time.Millisecond {
+		t.Errorf("expected QueryTime 23ms, got %v", stats.QueryTime)
+	}
+
+	wantServer := netip.MustParseAddrPort("1.1.1.1:53")
+	if stats.Server != wantServer {
+		t.Errorf("expected Server %v, got %v", wantServer, stats.Server)
+	}
+
+	if stats.ServerName != "1.1.1.1" {
+		t.Errorf("expected ServerName '1.1.1.1', got %q", stats.ServerName)
+	}
+
+	if stats.Transport != "UDP" {
+		t.Errorf("expected Transport 'UDP', got %q", stats.Transport)
+	}
+
+	wantWhen := time.Date(2026, time.July, 26, 12, 0, 0, 0, time.UTC)
+	if !stats.When.Equal(wantWhen) {
+		t.Errorf("expected When %v, got %v", wantWhen, stats.When)
+	}
+
+	if stats.MsgSize != 56 {
+		t.Errorf("expected MsgSize 56, got %d", stats.MsgSize)
+	}
+
+	if messages[0].Msg.Id != 45678 {
+		t.Errorf("expected message id 45678, got %d", messages[0].Msg.Id)
+	}
+}
+
+func TestParseDigOutputWithStatsLongLine(t *testing.T) {
+	// Regression test: ParseDigOutputWithStats used to run its own
+	// unbuffered bufio.Scanner loop instead of sharing ParseDigStream's
+	// scanning engine (scanDigStream), so it returned bufio.ErrTooLong on
+	// lines over the default 64KiB token size.
+	longValue := strings.Repeat("a", 100*1024)
+	data := `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1
+;; flags: qr rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;example.com.			IN	TXT
+
+;; ANSWER SECTION:
+example.com.		300	IN	TXT	"` + longValue + `"
+
+;; Query time: 23 msec
+;; SERVER: 1.1.1.1#53(1.1.1.1) (UDP)
+;; MSG SIZE  rcvd: 56
+`
+
+	messages, err := ParseDigOutputWithStats(data)
+	if err != nil {
+		t.Fatalf("failed parsing long line: %v", err)
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+}
@@ -0,0 +1,154 @@
+package digparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// defaultScannerBufferSize is large enough to hold the long TXT/DNSSEC
+// record lines that overflow bufio.Scanner's 64KiB default token size.
+const defaultScannerBufferSize = 1024 * 1024
+
+// ParseDigStream parses dig output read from r, invoking fn with each
+// *dns.Msg as soon as its terminating boundary (the next ->>HEADER<<- line,
+// or EOF) is reached. It lets callers stream long-running `dig +qr` loops,
+// mass-resolver captures or tcpdump-style pipelines without buffering the
+// entire input. fn is called synchronously; a non-nil error from fn stops
+// the scan and is returned to the caller.
+func ParseDigStream(r io.Reader, fn func(*dns.Msg) error) error {
+	return ParseDigStreamWithBufferSize(r, defaultScannerBufferSize, fn)
+}
+
+// ParseDigStreamWithBufferSize behaves like ParseDigStream but lets callers
+// raise the bufio.Scanner buffer above defaultScannerBufferSize, for
+// transcripts with lines even longer than that.
+func ParseDigStreamWithBufferSize(r io.Reader, bufferSize int, fn func(*dns.Msg) error) error {
+	return scanDigStream(r, bufferSize, fn, nil)
+}
+
+// scanDigStream is the scanning engine shared by ParseDigStream and
+// ParseDigOutputWithStats: it reads r line by line, dispatches each line to
+// the right section parser, reassembles +multiline RRs, and calls onMessage
+// once a message's boundary (the next ->>HEADER<<- line, or EOF) is reached.
+// onStatsLine, if non-nil, receives the trailing dig stats footer lines
+// (";; Query time: ...", ";; SERVER: ...", etc.) instead of leaving them to
+// fall through to whatever section parser was last active.
+func scanDigStream(r io.Reader, bufferSize int, onMessage func(*dns.Msg) error, onStatsLine func(line string) error) error {
+	const (
+		headerSection     = ";; ->>HEADER<<- "
+		flagsSection      = ";; flags: "
+		questionSection   = ";; QUESTION SECTION:"
+		answerSection     = ";; ANSWER SECTION:"
+		authoritySection  = ";; AUTHORITY SECTION:"
+		additionalSection = ";; ADDITIONAL SECTION:"
+		optSection        = ";; OPT PSEUDOSECTION:"
+		queryTimePrefix   = ";; Query time:"
+		serverPrefix      = ";; SERVER:"
+		whenPrefix        = ";; WHEN:"
+		msgSizePrefix     = ";; MSG SIZE"
+	)
+
+	newMsg := func() *dns.Msg {
+		return &dns.Msg{
+			Question: []dns.Question{},
+			Answer:   []dns.RR{},
+			Extra:    []dns.RR{},
+		}
+	}
+
+	m := newMsg()
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), bufferSize)
+
+	currentParser := (ParserFunc)(nil)
+	currentSection := ""
+
+	var pendingRR []string
+	parenDepth := 0
+
+	for s.Scan() {
+		line := s.Text()
+
+		switch {
+		case strings.HasPrefix(line, headerSection):
+			currentParser = sectionParsers["HEADER"]
+			currentSection = "HEADER"
+			if m.Id > 0 {
+				if err := onMessage(m); err != nil {
+					return err
+				}
+				m = newMsg()
+			}
+		case strings.HasPrefix(line, flagsSection):
+			currentParser = sectionParsers["FLAGS"]
+			currentSection = "FLAGS"
+		case strings.HasPrefix(line, questionSection):
+			currentParser = sectionParsers["QUESTION"]
+			currentSection = "QUESTION"
+			continue
+		case strings.HasPrefix(line, answerSection):
+			currentParser = sectionParsers["ANSWER"]
+			currentSection = "ANSWER"
+			continue
+		case strings.HasPrefix(line, authoritySection):
+			currentParser = sectionParsers["AUTHORITY"]
+			currentSection = "AUTHORITY"
+			continue
+		case strings.HasPrefix(line, additionalSection):
+			currentParser = sectionParsers["ADDITIONAL"]
+			currentSection = "ADDITIONAL"
+			continue
+		case strings.HasPrefix(line, optSection):
+			currentParser = sectionParsers["OPT"]
+			currentSection = "OPT"
+			continue
+		case onStatsLine != nil && (strings.HasPrefix(line, queryTimePrefix) ||
+			strings.HasPrefix(line, serverPrefix) ||
+			strings.HasPrefix(line, whenPrefix) ||
+			strings.HasPrefix(line, msgSizePrefix)):
+			currentParser = nil
+			if err := onStatsLine(line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if currentParser == nil {
+			continue
+		}
+
+		if isRecordSection(currentSection) {
+			if parenDepth > 0 || strings.Contains(line, "(") {
+				pendingRR = append(pendingRR, line)
+				parenDepth += strings.Count(line, "(") - strings.Count(line, ")")
+				if parenDepth > 0 {
+					continue
+				}
+
+				line = strings.Join(pendingRR, " ")
+				pendingRR = nil
+			}
+		}
+
+		if err := currentParser(line, m); err != nil {
+			return fmt.Errorf("failed to parse section: %v", err)
+		}
+	}
+
+	if m.Id > 0 {
+		if err := onMessage(m); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("error reading input: %v", err)
+	}
+
+	return nil
+}
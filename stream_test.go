@@ -0,0 +1,86 @@
+package digparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseDigStreamLongLine(t *testing.T) {
+	// A TXT record with a value longer than bufio.Scanner's 64KiB default
+	// token size used to make the scanner return bufio.ErrTooLong.
+	longValue := strings.Repeat("a", 100*1024)
+	data := `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1
+;; flags: qr rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;example.com.			IN	TXT
+
+;; ANSWER SECTION:
+example.com.		300	IN	TXT	"` + longValue + `"
+
+`
+
+	var got []*dns.Msg
+
+	err := ParseDigStream(strings.NewReader(data), func(m *dns.Msg) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed parsing long line: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(got))
+	}
+}
+
+const benchMessageTemplate = `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1
+;; flags: qr rd ra; QUERY: 1, ANSWER: 1, AUTHORITY: 0, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;example.com.			IN	A
+
+;; ANSWER SECTION:
+example.com.		300	IN	A	93.184.216.34
+
+`
+
+func benchmarkData(messages int) string {
+	var sb strings.Builder
+
+	for i := 0; i < messages; i++ {
+		sb.WriteString(benchMessageTemplate)
+	}
+
+	return sb.String()
+}
+
+func BenchmarkParseDigOutput(b *testing.B) {
+	data := benchmarkData(20000)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseDigOutput(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseDigStream(b *testing.B) {
+	data := benchmarkData(20000)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := ParseDigStream(strings.NewReader(data), func(m *dns.Msg) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}